@@ -0,0 +1,78 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package driver
+
+import (
+	"strings"
+	"text/template"
+)
+
+// ServerBootstrapper produces the Cloud.dk template identifier and the
+// cloud-init user-data document used to configure a freshly created server.
+// Implementations exist per supported OS image, so that adding support for
+// a new image is a matter of adding a bootstrapper rather than touching
+// CloudServer.Create.
+type ServerBootstrapper interface {
+	// Template returns the Cloud.dk template identifier to request when
+	// creating the server (e.g. "ubuntu-22.04-x64").
+	Template() string
+
+	// UserData renders the cloud-init user-data document applied on first
+	// boot. publicKey is authorized for SSH access and extraUserData is
+	// appended verbatim so operators can inject site-specific setup.
+	UserData(publicKey string, extraUserData string) (string, error)
+}
+
+// bootstrapData is the set of values available to a bootstrapper's
+// cloud-config template.
+type bootstrapData struct {
+	PublicKey     string
+	MirrorURL     string
+	ExtraUserData string
+}
+
+// renderCloudConfig executes a cloud-config template with the given data.
+func renderCloudConfig(name string, text string, data bootstrapData) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	err = tmpl.Execute(&b, data)
+
+	if err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// cloudConfigTemplate is shared by every apt-based bootstrapper. It disables
+// swap, points apt at a mirror, authorizes the driver's SSH key, hardens
+// sshd to key-only authentication and installs the packages the node
+// plugin will later need for iSCSI/NFS volumes.
+const cloudConfigTemplate = `#cloud-config
+package_update: true
+packages:
+  - open-iscsi
+  - nfs-common
+bootcmd:
+  - swapoff -a
+  - sed -i '/ swap / s/^/#/' /etc/fstab
+ssh_authorized_keys:
+  - {{.PublicKey}}
+apt:
+  preserve_sources_list: false
+  primary:
+    - arches: [default]
+      uri: {{.MirrorURL}}
+runcmd:
+  - sed -i 's/#\?PasswordAuthentication.*/PasswordAuthentication no/' /etc/ssh/sshd_config
+  - systemctl restart ssh
+{{if .ExtraUserData}}
+{{.ExtraUserData}}
+{{end}}`
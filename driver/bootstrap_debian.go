@@ -0,0 +1,22 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package driver
+
+// Debian11Bootstrapper bootstraps servers running Debian 11 ("bullseye").
+type Debian11Bootstrapper struct{}
+
+// Template returns the Cloud.dk template identifier.
+func (b *Debian11Bootstrapper) Template() string {
+	return "debian-11-x64"
+}
+
+// UserData renders the cloud-init user-data document.
+func (b *Debian11Bootstrapper) UserData(publicKey string, extraUserData string) (string, error) {
+	return renderCloudConfig("debian-11", cloudConfigTemplate, bootstrapData{
+		PublicKey:     publicKey,
+		MirrorURL:     "http://mirrors.dotsrc.org/debian",
+		ExtraUserData: extraUserData,
+	})
+}
@@ -0,0 +1,40 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package driver
+
+// Ubuntu2004Bootstrapper bootstraps servers running Ubuntu 20.04.
+type Ubuntu2004Bootstrapper struct{}
+
+// Template returns the Cloud.dk template identifier.
+func (b *Ubuntu2004Bootstrapper) Template() string {
+	return "ubuntu-20.04-x64"
+}
+
+// UserData renders the cloud-init user-data document.
+func (b *Ubuntu2004Bootstrapper) UserData(publicKey string, extraUserData string) (string, error) {
+	return renderCloudConfig("ubuntu-20.04", cloudConfigTemplate, bootstrapData{
+		PublicKey:     publicKey,
+		MirrorURL:     "http://mirrors.dotsrc.org/ubuntu",
+		ExtraUserData: extraUserData,
+	})
+}
+
+// Ubuntu2204Bootstrapper bootstraps servers running Ubuntu 22.04. It is the
+// default bootstrapper when none is configured.
+type Ubuntu2204Bootstrapper struct{}
+
+// Template returns the Cloud.dk template identifier.
+func (b *Ubuntu2204Bootstrapper) Template() string {
+	return "ubuntu-22.04-x64"
+}
+
+// UserData renders the cloud-init user-data document.
+func (b *Ubuntu2204Bootstrapper) UserData(publicKey string, extraUserData string) (string, error) {
+	return renderCloudConfig("ubuntu-22.04", cloudConfigTemplate, bootstrapData{
+		PublicKey:     publicKey,
+		MirrorURL:     "http://mirrors.dotsrc.org/ubuntu",
+		ExtraUserData: extraUserData,
+	})
+}
@@ -0,0 +1,183 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+// Package cloudservertest implements a self-check that exercises the full
+// Cloud.dk server lifecycle (list, create, SSH, destroy) against a live
+// account. It is meant to be run by operators before installing the CSI
+// driver into a cluster, to validate credentials, templates and SSH keys
+// up front rather than discovering a misconfiguration mid-provisioning.
+package cloudservertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/danitso/terraform-provider-clouddk/clouddk"
+	"github.com/google/uuid"
+	"github.com/isgasho/clouddk-csi-driver/driver"
+)
+
+// HostnamePrefix marks every server created by the self-check, so that a
+// re-run can recognize and reap instances left behind by a prior failure.
+const HostnamePrefix = "csi-selftest-"
+
+// Config describes a single self-check run.
+type Config struct {
+	// Driver supplies the Cloud.dk client settings and SSH credentials
+	// under test.
+	Driver *driver.Driver
+
+	// LocationID and PackageID select where and what size of server to
+	// create for the duration of the probe.
+	LocationID string
+	PackageID  string
+
+	// ProbeCommand is executed over SSH on the freshly booted server to
+	// confirm it is usable, not merely reachable. Defaults to "true".
+	ProbeCommand string
+}
+
+// Result summarizes the outcome of a self-check run.
+type Result struct {
+	// ExistingInstances is the number of servers visible to the API token
+	// before this run started, excluding any that were cleaned up as leaks.
+	// Servers are not filtered by the driver's label, so servers the driver
+	// did not create are counted too.
+	ExistingInstances int
+
+	// LeakedInstancesCleaned counts self-check servers from prior failed
+	// runs that were destroyed before the new probe began.
+	LeakedInstancesCleaned int
+
+	// ProbeOutput is the combined stdout/stderr of the probe command.
+	ProbeOutput string
+}
+
+// Run lists every server visible to the API token, reaps any leaked self-check
+// instances from previous runs, creates a new server, waits for it to
+// accept SSH connections, runs the probe command against it, and finally
+// destroys it. Each step is logged so operators can pinpoint exactly which
+// part of the Cloud.dk setup is broken.
+func Run(cfg Config) (*Result, error) {
+	if cfg.Driver == nil {
+		return nil, fmt.Errorf("a driver instance is required")
+	}
+
+	if cfg.LocationID == "" || cfg.PackageID == "" {
+		return nil, fmt.Errorf("a location ID and package ID are required")
+	}
+
+	probeCommand := cfg.ProbeCommand
+
+	if probeCommand == "" {
+		probeCommand = "true"
+	}
+
+	result := &Result{}
+
+	log.Printf("cloudservertest: listing existing servers")
+
+	servers, err := listServers(cfg.Driver)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list existing servers, check the API token: %w", err)
+	}
+
+	for _, s := range servers {
+		if !strings.HasPrefix(s.Hostname, HostnamePrefix) {
+			continue
+		}
+
+		log.Printf("cloudservertest: destroying leaked instance from a previous run (hostname: %s)", s.Hostname)
+
+		leaked := &driver.CloudServer{Driver: cfg.Driver, Information: s}
+
+		if err := leaked.Destroy(); err != nil {
+			return nil, fmt.Errorf("failed to clean up leaked instance '%s': %w", s.Hostname, err)
+		}
+
+		result.LeakedInstancesCleaned++
+	}
+
+	result.ExistingInstances = len(servers) - result.LeakedInstancesCleaned
+
+	hostname := HostnamePrefix + uuid.New().String()
+
+	log.Printf("cloudservertest: creating probe server (hostname: %s)", hostname)
+
+	server := &driver.CloudServer{Driver: cfg.Driver}
+
+	err = server.Create(cfg.LocationID, cfg.PackageID, hostname)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a probe server, check the location/package IDs and SSH key: %w", err)
+	}
+
+	defer func() {
+		log.Printf("cloudservertest: destroying probe server (hostname: %s)", hostname)
+
+		if err := server.Destroy(); err != nil {
+			log.Printf("cloudservertest: failed to destroy probe server (hostname: %s): %v", hostname, err)
+		}
+	}()
+
+	log.Printf("cloudservertest: running probe command over SSH (hostname: %s)", hostname)
+
+	sshClient, err := server.SSH()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to establish an SSH connection to the probe server: %w", err)
+	}
+
+	defer sshClient.Close()
+
+	sshSession, err := sshClient.NewSession()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open an SSH session on the probe server: %w", err)
+	}
+
+	defer sshSession.Close()
+
+	output, err := sshSession.CombinedOutput(probeCommand)
+
+	result.ProbeOutput = string(output)
+
+	if err != nil {
+		return result, fmt.Errorf("probe command failed on the probe server: %w", err)
+	}
+
+	log.Printf("cloudservertest: self-check passed (existing instances: %d, leaked instances cleaned: %d)", result.ExistingInstances, result.LeakedInstancesCleaned)
+
+	return result, nil
+}
+
+// listServers retrieves every server visible to the configured API token.
+func listServers(d *driver.Driver) (clouddk.ServerListBody, error) {
+	res, err := clouddk.DoClientRequest(
+		d.Configuration.ClientSettings,
+		"GET",
+		"cloudservers",
+		new(bytes.Buffer),
+		[]int{200},
+		1,
+		1,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	servers := make(clouddk.ServerListBody, 0)
+	err = json.NewDecoder(res.Body).Decode(&servers)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return servers, nil
+}
@@ -0,0 +1,20 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package driver
+
+import (
+	"golang.org/x/crypto/ssh"
+)
+
+// HostKeyStore persists the SSH host key observed for a Cloud.dk server on
+// its first successful connection, so that later connections can verify
+// against it instead of trusting the network blindly.
+type HostKeyStore interface {
+	// GetHostKey retrieves the stored host key for a server identifier.
+	GetHostKey(identifier string) (ssh.PublicKey, error)
+
+	// SetHostKey stores the host key for a server identifier.
+	SetHostKey(identifier string, key ssh.PublicKey) error
+}
@@ -0,0 +1,87 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package driver
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SecretHostKeyStore is the default HostKeyStore: it persists every
+// server's host key as a data entry (keyed by server identifier) in a
+// single Kubernetes Secret, so a pinned host key survives driver restarts.
+type SecretHostKeyStore struct {
+	// Secrets is a client scoped to the namespace the driver runs in.
+	Secrets corev1client.SecretInterface
+
+	// SecretName is the Secret used to store host keys, created on first
+	// use if it does not already exist.
+	SecretName string
+}
+
+// GetHostKey retrieves the stored host key for a server identifier.
+func (s *SecretHostKeyStore) GetHostKey(identifier string) (ssh.PublicKey, error) {
+	secret, err := s.Secrets.Get(context.Background(), s.SecretName, metav1.GetOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	raw, ok := secret.Data[identifier]
+
+	if !ok {
+		return nil, fmt.Errorf("no host key stored for server '%s'", identifier)
+	}
+
+	key, _, _, _, err := ssh.ParseAuthorizedKey(raw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// SetHostKey stores the host key for a server identifier, creating the
+// backing Secret on first use.
+func (s *SecretHostKeyStore) SetHostKey(identifier string, key ssh.PublicKey) error {
+	marshaled := ssh.MarshalAuthorizedKey(key)
+
+	secret, err := s.Secrets.Get(context.Background(), s.SecretName, metav1.GetOptions{})
+
+	if apierrors.IsNotFound(err) {
+		_, err = s.Secrets.Create(context.Background(), &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: s.SecretName,
+			},
+			Data: map[string][]byte{
+				identifier: marshaled,
+			},
+		}, metav1.CreateOptions{})
+
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+
+	secret.Data[identifier] = marshaled
+
+	_, err = s.Secrets.Update(context.Background(), secret, metav1.UpdateOptions{})
+
+	return err
+}
@@ -5,12 +5,24 @@
 package driver
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"os/exec"
+	"strings"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/danitso/terraform-provider-clouddk/clouddk"
 	"github.com/golang/protobuf/ptypes/wrappers"
+	"golang.org/x/crypto/ssh"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// nodeMountTools lists the binaries the node service needs on the host to
+// attach the block/NFS volumes the controller hands out.
+var nodeMountTools = []string{"mount.nfs", "iscsiadm"}
+
 // IdentityServer implements the csi.IdentityServer interface.
 type IdentityServer struct {
 	driver *Driver
@@ -41,6 +53,20 @@ func (is *IdentityServer) GetPluginCapabilities(ctx context.Context, req *csi.Ge
 					},
 				},
 			},
+			{
+				Type: &csi.PluginCapability_Service_{
+					Service: &csi.PluginCapability_Service{
+						Type: csi.PluginCapability_Service_GROUP_CONTROLLER_SERVICE,
+					},
+				},
+			},
+			{
+				Type: &csi.PluginCapability_VolumeExpansion_{
+					VolumeExpansion: &csi.PluginCapability_VolumeExpansion{
+						Type: csi.PluginCapability_VolumeExpansion_ONLINE,
+					},
+				},
+			},
 		},
 	}
 
@@ -57,11 +83,40 @@ func (is *IdentityServer) GetPluginInfo(ctx context.Context, req *csi.GetPluginI
 	return resp, nil
 }
 
-// Probe returns the health and readiness of the plugin.
+// Probe returns the health and readiness of the plugin. It is only ready
+// once the Cloud.dk API is reachable, the configured SSH private key
+// parses, and, when running as the node service, the mount tooling the
+// node plugin depends on is installed on the host.
 func (is *IdentityServer) Probe(ctx context.Context, req *csi.ProbeRequest) (*csi.ProbeResponse, error) {
+	var failures []string
+
+	_, err := clouddk.DoClientRequest(is.driver.Configuration.ClientSettings, "GET", "cloudservers?limit=1", new(bytes.Buffer), []int{200}, 1, 1)
+
+	if err != nil {
+		failures = append(failures, fmt.Sprintf("the Cloud.dk API is unreachable: %v", err))
+	}
+
+	_, err = ssh.ParsePrivateKey([]byte(is.driver.Configuration.PrivateKey))
+
+	if err != nil {
+		failures = append(failures, fmt.Sprintf("the configured SSH private key does not parse: %v", err))
+	}
+
+	if is.driver.NodeID != "" {
+		for _, tool := range nodeMountTools {
+			if _, err := exec.LookPath(tool); err != nil {
+				failures = append(failures, fmt.Sprintf("required mount tool %q is not installed: %v", tool, err))
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		return nil, status.Error(codes.FailedPrecondition, strings.Join(failures, "; "))
+	}
+
 	return &csi.ProbeResponse{
 		Ready: &wrappers.BoolValue{
-			Value: false,
+			Value: true,
 		},
 	}, nil
 }
\ No newline at end of file
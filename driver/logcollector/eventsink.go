@@ -0,0 +1,42 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package logcollector
+
+import (
+	"errors"
+	"fmt"
+)
+
+// EventRecorder is the minimal surface EventSink needs to raise a
+// Kubernetes Event, so that this package does not have to depend on
+// client-go directly. Callers typically satisfy it with a thin adapter
+// around a real client-go EventRecorder and the PVC/Node object reference.
+type EventRecorder interface {
+	Event(eventType, reason, message string)
+}
+
+// EventSink ships a Bundle as a Kubernetes Event on the object (PVC or
+// Node) that Recorder was constructed for.
+type EventSink struct {
+	Recorder EventRecorder
+}
+
+// Ship raises a Warning event summarizing the bundle.
+func (s *EventSink) Ship(bundle Bundle) error {
+	if s.Recorder == nil {
+		return errors.New("logcollector: EventSink has no recorder configured")
+	}
+
+	message := fmt.Sprintf(
+		"bootstrap failed on %s while running %q: %s",
+		bundle.Hostname,
+		bundle.FailureCommand,
+		bundle.FailureOutput,
+	)
+
+	s.Recorder.Event("Warning", "BootstrapFailed", message)
+
+	return nil
+}
@@ -0,0 +1,100 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+// Package logcollector captures best-effort diagnostic logs from a Cloud.dk
+// server after a bootstrap or SSH-dial failure, so that field debugging of
+// "failed to bootstrap" does not start from a single opaque error. Captured
+// bundles are shipped through a pluggable Sink (an S3-compatible object
+// store or a Kubernetes Event).
+package logcollector
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// commands gathers the log sources useful for diagnosing a failed
+// bootstrap. Each is run independently and best-effort: a failing command
+// contributes its own error text instead of aborting the whole collection.
+var commands = map[string]string{
+	"cloud-init.log": "cat /var/log/cloud-init.log",
+	"ssh-journal":    "journalctl -u ssh --no-pager",
+	"dmesg":          "dmesg",
+}
+
+// Bundle is a snapshot of diagnostic logs captured from a single server.
+type Bundle struct {
+	Hostname   string
+	CapturedAt time.Time
+
+	// Logs maps a log source name (a key of commands) to its captured,
+	// redacted output.
+	Logs map[string]string
+
+	// FailureCommand and FailureOutput describe the step that triggered
+	// the collection, if any.
+	FailureCommand string
+	FailureOutput  string
+}
+
+// Sink ships a captured Bundle somewhere an operator can read it.
+type Sink interface {
+	Ship(bundle Bundle) error
+}
+
+// Collect opens one SSH session per diagnostic command on sshClient and
+// gathers the output of each into a Bundle. Any of secrets found in the
+// captured text (e.g. the bootstrap root password or the driver's private
+// key) is replaced before it is returned, so that a shipped bundle never
+// leaks credentials.
+func Collect(sshClient *ssh.Client, hostname string, failureCommand string, failureOutput string, secrets ...string) Bundle {
+	bundle := Bundle{
+		Hostname:       hostname,
+		CapturedAt:     time.Now(),
+		Logs:           make(map[string]string, len(commands)),
+		FailureCommand: failureCommand,
+		FailureOutput:  Redact(secrets, failureOutput),
+	}
+
+	for name, cmd := range commands {
+		session, err := sshClient.NewSession()
+
+		if err != nil {
+			bundle.Logs[name] = fmt.Sprintf("failed to open SSH session: %v", err)
+
+			continue
+		}
+
+		output, err := session.CombinedOutput(cmd)
+		session.Close()
+
+		if err != nil {
+			bundle.Logs[name] = Redact(secrets, fmt.Sprintf("failed to run %q: %v\n%s", cmd, err, output))
+
+			continue
+		}
+
+		bundle.Logs[name] = Redact(secrets, string(output))
+	}
+
+	return bundle
+}
+
+// Redact replaces every occurrence of each non-empty secret in text. It is
+// exported so that callers building a Bundle without going through Collect
+// (e.g. when no SSH session was ever established) can still scrub it.
+func Redact(secrets []string, text string) string {
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+
+		text = strings.ReplaceAll(text, secret, "***REDACTED***")
+	}
+
+	return text
+}
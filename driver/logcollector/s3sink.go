@@ -0,0 +1,64 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package logcollector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// S3Sink ships a Bundle as a JSON object to an S3-compatible object store.
+// BaseURL must already point at the target bucket (e.g.
+// "https://s3.example.com/my-bucket") and, since this sink performs a plain
+// PUT rather than signing the request, is expected to either be writable
+// anonymously or to embed short-lived credentials (such as a pre-signed
+// URL) supplied by the caller.
+type S3Sink struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// Ship uploads bundle as "<hostname>/<capturedAt>.json" under BaseURL.
+func (s *S3Sink) Ship(bundle Bundle) error {
+	body, err := json.Marshal(bundle)
+
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s/%s.json", bundle.Hostname, bundle.CapturedAt.UTC().Format("20060102T150405Z"))
+	url := strings.TrimRight(s.BaseURL, "/") + "/" + key
+
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := s.HTTPClient
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("object store rejected the log bundle with status %d", res.StatusCode)
+	}
+
+	return nil
+}
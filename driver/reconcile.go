@@ -0,0 +1,140 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package driver
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/danitso/terraform-provider-clouddk/clouddk"
+)
+
+// Reconcile periodically sweeps for servers carrying the driver's label tag
+// (see labelSeparator in server.go) that are not tracked by any current CSI
+// volume, and destroys any that have stayed untracked for at least
+// gracePeriod. isTracked is consulted for every driver-owned hostname found;
+// it is expected to check the current volume/node inventory. Reconcile
+// blocks until stopCh is closed, so callers should run it in its own
+// goroutine.
+func (d *Driver) Reconcile(interval time.Duration, gracePeriod time.Duration, isTracked func(hostname string) bool, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	orphanedSince := make(map[string]time.Time)
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			d.reconcileOnce(gracePeriod, isTracked, orphanedSince)
+		}
+	}
+}
+
+// reconcileOnce runs a single sweep, mutating orphanedSince to track how
+// long each untracked hostname has been seen.
+func (d *Driver) reconcileOnce(gracePeriod time.Duration, isTracked func(hostname string) bool, orphanedSince map[string]time.Time) {
+	servers, err := d.listTaggedServers()
+
+	if err != nil {
+		debugCloudAction(rtServers, "Reconcile: failed to list cloud servers: %v", err)
+
+		return
+	}
+
+	byHostname := make(map[string]clouddk.ServerBody, len(servers))
+	hostnames := make([]string, 0, len(servers))
+
+	for _, server := range servers {
+		byHostname[server.Hostname] = server
+		hostnames = append(hostnames, server.Hostname)
+	}
+
+	for _, hostname := range updateOrphanTracking(hostnames, isTracked, gracePeriod, time.Now(), orphanedSince) {
+		debugCloudAction(rtServers, "Reconcile: destroying untracked cloud server past its grace period (hostname: %s)", hostname)
+
+		orphan := &CloudServer{Driver: d, Information: byHostname[hostname]}
+
+		if err := orphan.Destroy(); err != nil {
+			debugCloudAction(rtServers, "Reconcile: failed to destroy untracked cloud server (hostname: %s): %v", hostname, err)
+
+			continue
+		}
+
+		delete(orphanedSince, hostname)
+	}
+}
+
+// updateOrphanTracking is the pure bookkeeping core of reconcileOnce: given
+// the hostnames seen in the latest sweep, it updates orphanedSince and
+// returns the hostnames that have now been untracked for at least
+// gracePeriod and should be destroyed. Splitting it out of reconcileOnce
+// keeps the grace-period logic testable without a live Cloud.dk account.
+func updateOrphanTracking(hostnames []string, isTracked func(hostname string) bool, gracePeriod time.Duration, now time.Time, orphanedSince map[string]time.Time) []string {
+	seen := make(map[string]bool, len(hostnames))
+
+	var toDestroy []string
+
+	for _, hostname := range hostnames {
+		seen[hostname] = true
+
+		if isTracked(hostname) {
+			delete(orphanedSince, hostname)
+
+			continue
+		}
+
+		firstSeen, ok := orphanedSince[hostname]
+
+		if !ok {
+			orphanedSince[hostname] = now
+
+			continue
+		}
+
+		if now.Sub(firstSeen) < gracePeriod {
+			continue
+		}
+
+		toDestroy = append(toDestroy, hostname)
+	}
+
+	for hostname := range orphanedSince {
+		if !seen[hostname] {
+			delete(orphanedSince, hostname)
+		}
+	}
+
+	return toDestroy
+}
+
+// listTaggedServers retrieves every server that carries the driver's label
+// tag, i.e. was created through CloudServer.Create.
+func (d *Driver) listTaggedServers() (clouddk.ServerListBody, error) {
+	res, err := clouddk.DoClientRequest(d.Configuration.ClientSettings, "GET", "cloudservers", new(bytes.Buffer), []int{200}, 1, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	servers := make(clouddk.ServerListBody, 0)
+	err = json.NewDecoder(res.Body).Decode(&servers)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tagged := make(clouddk.ServerListBody, 0, len(servers))
+
+	for _, server := range servers {
+		if hasDriverLabel(server.Label) {
+			tagged = append(tagged, server)
+		}
+	}
+
+	return tagged, nil
+}
@@ -0,0 +1,90 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package driver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHasDriverLabel(t *testing.T) {
+	const validUUID = "550e8400-e29b-41d4-a716-446655440000"
+
+	cases := []struct {
+		label string
+		want  bool
+	}{
+		{"web-1" + labelSeparator + validUUID, true},
+		{"web-1" + labelSeparator + "not-a-uuid", false},
+		{"web-1", false},
+		{"customer-owned" + labelSeparator + "server", false},
+		{"", false},
+	}
+
+	for _, c := range cases {
+		if got := hasDriverLabel(c.label); got != c.want {
+			t.Errorf("hasDriverLabel(%q) = %v, want %v", c.label, got, c.want)
+		}
+	}
+}
+
+func TestUpdateOrphanTrackingGracePeriod(t *testing.T) {
+	gracePeriod := 10 * time.Minute
+	now := time.Unix(0, 0)
+	isTracked := func(hostname string) bool { return false }
+	orphanedSince := make(map[string]time.Time)
+
+	toDestroy := updateOrphanTracking([]string{"orphan-1"}, isTracked, gracePeriod, now, orphanedSince)
+
+	if len(toDestroy) != 0 {
+		t.Fatalf("a freshly seen hostname should not be destroyed yet, got %v", toDestroy)
+	}
+
+	toDestroy = updateOrphanTracking([]string{"orphan-1"}, isTracked, gracePeriod, now.Add(gracePeriod/2), orphanedSince)
+
+	if len(toDestroy) != 0 {
+		t.Fatalf("a hostname within its grace period should not be destroyed, got %v", toDestroy)
+	}
+
+	toDestroy = updateOrphanTracking([]string{"orphan-1"}, isTracked, gracePeriod, now.Add(2*gracePeriod), orphanedSince)
+
+	if len(toDestroy) != 1 || toDestroy[0] != "orphan-1" {
+		t.Fatalf("a hostname past its grace period should be destroyed, got %v", toDestroy)
+	}
+}
+
+func TestUpdateOrphanTrackingTrackedHostnameIsForgotten(t *testing.T) {
+	gracePeriod := 10 * time.Minute
+	now := time.Unix(0, 0)
+	orphanedSince := make(map[string]time.Time)
+
+	untracked := func(hostname string) bool { return false }
+	updateOrphanTracking([]string{"web-1"}, untracked, gracePeriod, now, orphanedSince)
+
+	if _, ok := orphanedSince["web-1"]; !ok {
+		t.Fatalf("an untracked hostname should be recorded in orphanedSince")
+	}
+
+	tracked := func(hostname string) bool { return true }
+	updateOrphanTracking([]string{"web-1"}, tracked, gracePeriod, now.Add(2*gracePeriod), orphanedSince)
+
+	if _, ok := orphanedSince["web-1"]; ok {
+		t.Fatalf("a hostname that becomes tracked again should be forgotten, not destroyed")
+	}
+}
+
+func TestUpdateOrphanTrackingDisappearedHostnameIsForgotten(t *testing.T) {
+	gracePeriod := 10 * time.Minute
+	now := time.Unix(0, 0)
+	orphanedSince := make(map[string]time.Time)
+	untracked := func(hostname string) bool { return false }
+
+	updateOrphanTracking([]string{"web-1"}, untracked, gracePeriod, now, orphanedSince)
+	updateOrphanTracking([]string{}, untracked, gracePeriod, now.Add(2*gracePeriod), orphanedSince)
+
+	if _, ok := orphanedSince["web-1"]; ok {
+		t.Fatalf("a hostname no longer returned by Cloud.dk should not linger in orphanedSince")
+	}
+}
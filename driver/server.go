@@ -6,18 +6,44 @@ package driver
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math/rand"
+	"net"
 	"net/url"
 	"strings"
 	"time"
 
 	"github.com/danitso/terraform-provider-clouddk/clouddk"
+	"github.com/google/uuid"
+	"github.com/isgasho/clouddk-csi-driver/driver/logcollector"
 	"golang.org/x/crypto/ssh"
 )
 
+// labelSeparator joins a hostname to the pending UUID stored in a server's
+// label, so that a reconciliation lookup by hostname can tell a server
+// created by a specific Create() call apart from an unrelated one that
+// happens to share the hostname.
+const labelSeparator = "#"
+
+// hasDriverLabel reports whether label was produced by this driver, i.e.
+// ends in labelSeparator followed by a valid UUID. A bare substring check
+// for labelSeparator would also match an unrelated, manually-labeled
+// Cloud.dk server, which must never be swept up by Reconcile.
+func hasDriverLabel(label string) bool {
+	idx := strings.LastIndex(label, labelSeparator)
+
+	if idx == -1 {
+		return false
+	}
+
+	_, err := uuid.Parse(label[idx+len(labelSeparator):])
+
+	return err == nil
+}
+
 // CloudServer manages a Cloud.dk server.
 type CloudServer struct {
 	Driver      *Driver
@@ -32,19 +58,32 @@ func (s *CloudServer) Create(locationID string, packageID string, hostname strin
 
 	debugCloudAction(rtServers, "Creating cloud server (hostname: %s)", hostname)
 
+	bootstrapper := s.Driver.Configuration.ServerBootstrapper
+
+	if bootstrapper == nil {
+		bootstrapper = &Ubuntu2204Bootstrapper{}
+	}
+
+	userData, err := bootstrapper.UserData(strings.TrimSpace(s.Driver.Configuration.PublicKey), s.Driver.Configuration.ExtraUserData)
+
+	if err != nil {
+		return err
+	}
+
 	rootPassword := "p" + s.GetRandomPassword(63)
+	pendingUUID := uuid.New().String()
 
 	body := clouddk.ServerCreateBody{
 		Hostname:            hostname,
-		Label:               hostname,
+		Label:               hostname + labelSeparator + pendingUUID,
 		InitialRootPassword: rootPassword,
 		Package:             packageID,
-		Template:            "ubuntu-18.04-x64",
+		Template:            bootstrapper.Template(),
 		Location:            locationID,
 	}
 
 	reqBody := new(bytes.Buffer)
-	err := json.NewEncoder(reqBody).Encode(body)
+	err = json.NewEncoder(reqBody).Encode(body)
 
 	if err != nil {
 		return err
@@ -53,16 +92,30 @@ func (s *CloudServer) Create(locationID string, packageID string, hostname strin
 	res, err := clouddk.DoClientRequest(s.Driver.Configuration.ClientSettings, "POST", "cloudservers", reqBody, []int{200}, 1, 1)
 
 	if err != nil {
-		debugCloudAction(rtServers, "Failed to create cloud server (hostname: %s)", hostname)
+		debugCloudAction(rtServers, "Failed to create cloud server, reconciling in case it was created despite the error (hostname: %s)", hostname)
 
-		return err
-	}
+		s.reconcileOrphan(hostname, pendingUUID)
 
-	s.Information = clouddk.ServerBody{}
-	err = json.NewDecoder(res.Body).Decode(&s.Information)
+		if s.Information.Identifier == "" {
+			return err
+		}
 
-	if err != nil {
-		return err
+		debugCloudAction(rtServers, "Adopted cloud server created despite a request error (hostname: %s)", hostname)
+	} else {
+		s.Information = clouddk.ServerBody{}
+		err = json.NewDecoder(res.Body).Decode(&s.Information)
+
+		if err != nil {
+			s.Information = clouddk.ServerBody{}
+
+			s.reconcileOrphan(hostname, pendingUUID)
+
+			if s.Information.Identifier == "" {
+				return err
+			}
+
+			debugCloudAction(rtServers, "Adopted cloud server created despite a response decode error (hostname: %s)", hostname)
+		}
 	}
 
 	if len(s.Information.NetworkInterfaces) == 0 {
@@ -79,11 +132,20 @@ func (s *CloudServer) Create(locationID string, packageID string, hostname strin
 	debugCloudAction(rtServers, "Waiting for cloud server to accept SSH connections (hostname: %s)", hostname)
 
 	var sshClient *ssh.Client
+	var capturedHostKey ssh.PublicKey
 
 	sshConfig := &ssh.ClientConfig{
-		User:            "root",
-		Auth:            []ssh.AuthMethod{ssh.Password(rootPassword)},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		User: "root",
+		Auth: []ssh.AuthMethod{ssh.Password(rootPassword)},
+		HostKeyCallback: func(h string, remote net.Addr, key ssh.PublicKey) error {
+			capturedHostKey = key
+
+			return nil
+		},
+	}
+
+	if s.Driver.Configuration.InsecureIgnoreHostKey {
+		sshConfig.HostKeyCallback = ssh.InsecureIgnoreHostKey()
 	}
 
 	timeDelay := int64(10)
@@ -112,6 +174,8 @@ func (s *CloudServer) Create(locationID string, packageID string, hostname strin
 	if err != nil {
 		debugCloudAction(rtServers, "Failed to create cloud server due to SSH timeout (hostname: %s)", hostname)
 
+		s.collectFailureLogs(nil, hostname, rootPassword, "ssh dial", err.Error())
+
 		s.Destroy()
 
 		return err
@@ -119,14 +183,38 @@ func (s *CloudServer) Create(locationID string, packageID string, hostname strin
 
 	defer sshClient.Close()
 
+	if !s.Driver.Configuration.InsecureIgnoreHostKey && capturedHostKey != nil {
+		if s.Driver.Configuration.HostKeyStore == nil {
+			debugCloudAction(rtServers, "Failed to create cloud server due to missing HostKeyStore (hostname: %s)", hostname)
+
+			s.Destroy()
+
+			return errors.New("A HostKeyStore must be configured to pin SSH host keys, or InsecureIgnoreHostKey must be set to opt out")
+		}
+
+		err = s.Driver.Configuration.HostKeyStore.SetHostKey(s.Information.Identifier, capturedHostKey)
+
+		if err != nil {
+			debugCloudAction(rtServers, "Failed to persist SSH host key (hostname: %s)", hostname)
+
+			s.Destroy()
+
+			return err
+		}
+	}
+
 	s.Information.Booted = true
 
-	// Configure the server by installing the required software and authorizing the SSH key.
+	// Configure the server by seeding the cloud-init user-data assembled by
+	// the bootstrapper and letting cloud-init itself apply it, instead of
+	// running an imperative chain of shell commands.
 	sshSession, err := sshClient.NewSession()
 
 	if err != nil {
 		debugCloudAction(rtServers, "Failed to create cloud server due to SSH errors (hostname: %s)", hostname)
 
+		s.collectFailureLogs(sshClient, hostname, rootPassword, "ssh new-session", err.Error())
+
 		s.Destroy()
 
 		return err
@@ -134,18 +222,23 @@ func (s *CloudServer) Create(locationID string, packageID string, hostname strin
 
 	defer sshSession.Close()
 
-	_, err = sshSession.CombinedOutput(
-		"swapoff -a && " +
-			"sed -i '/ swap / s/^/#/' /etc/fstab && " +
-			fmt.Sprintf("echo '%s' >> ~/.ssh/authorized_keys && ", strings.TrimSpace(s.Driver.Configuration.PublicKey)) +
-			"sed -i 's/us.archive.ubuntu.com/mirrors.dotsrc.org/' /etc/apt/sources.list && " +
-			"sed -i 's/#\\?PasswordAuthentication.*/PasswordAuthentication no/' /etc/ssh/sshd_config && " +
-			"systemctl restart ssh",
-	)
+	encodedUserData := base64.StdEncoding.EncodeToString([]byte(userData))
+
+	bootstrapCommand := "mkdir -p /var/lib/cloud/seed/nocloud && " +
+		fmt.Sprintf("echo %s | base64 -d > /var/lib/cloud/seed/nocloud/user-data && ", encodedUserData) +
+		"touch /var/lib/cloud/seed/nocloud/meta-data && " +
+		"cloud-init clean --logs && " +
+		"cloud-init init --local && cloud-init init && " +
+		"cloud-init modules --mode=config && " +
+		"cloud-init modules --mode=final"
+
+	bootstrapOutput, err := sshSession.CombinedOutput(bootstrapCommand)
 
 	if err != nil {
 		debugCloudAction(rtServers, "Failed to create cloud server due to bootstrap errors (hostname: %s)", hostname)
 
+		s.collectFailureLogs(sshClient, hostname, rootPassword, bootstrapCommand, string(bootstrapOutput)+err.Error())
+
 		s.Destroy()
 
 		return err
@@ -183,14 +276,78 @@ func (s *CloudServer) Destroy() error {
 	return nil
 }
 
-// GetRandomPassword generates a random password of a fixed length.
+// collectFailureLogs captures and ships best-effort diagnostic logs after a
+// bootstrap or SSH-dial failure, if a log sink is configured. sshClient may
+// be nil when the failure happened before a session could be established
+// (e.g. the dial itself timed out), in which case only the failure command
+// and output are shipped.
+func (s *CloudServer) collectFailureLogs(sshClient *ssh.Client, hostname string, rootPassword string, failureCommand string, failureOutput string) {
+	if s.Driver.Configuration.LogSink == nil {
+		return
+	}
+
+	secrets := []string{rootPassword, s.Driver.Configuration.PrivateKey}
+
+	var bundle logcollector.Bundle
+
+	if sshClient != nil {
+		bundle = logcollector.Collect(sshClient, hostname, failureCommand, failureOutput, secrets...)
+	} else {
+		bundle = logcollector.Bundle{
+			Hostname:       hostname,
+			FailureCommand: failureCommand,
+			FailureOutput:  logcollector.Redact(secrets, failureOutput),
+		}
+	}
+
+	if err := s.Driver.Configuration.LogSink.Ship(bundle); err != nil {
+		debugCloudAction(rtServers, "Failed to ship bootstrap failure logs (hostname: %s): %v", hostname, err)
+	}
+}
+
+// reconcileOrphan looks up hostname after a failed Create() to find out
+// whether Cloud.dk actually created the server despite the error (e.g. the
+// POST timed out after the server was provisioned). A server is only
+// adopted into s.Information if its label carries the pendingUUID that was
+// submitted for this Create() call; an unrelated server with the same
+// hostname is left untouched.
+func (s *CloudServer) reconcileOrphan(hostname string, pendingUUID string) {
+	lookup := &CloudServer{Driver: s.Driver}
+	notFound, err := lookup.InitializeByHostname(hostname)
+
+	if notFound || err != nil {
+		return
+	}
+
+	if strings.HasSuffix(lookup.Information.Label, labelSeparator+pendingUUID) {
+		s.Information = lookup.Information
+	}
+}
+
+// GetRandomPassword generates a cryptographically secure random password of a fixed length.
 func (s *CloudServer) GetRandomPassword(length int) string {
+	const chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+	// Reject bytes beyond the largest multiple of len(chars) that fits in a
+	// byte, so that chars[b%len(chars)] stays uniform instead of favoring
+	// the low end of the character set.
+	maxByte := 256 - (256 % len(chars))
+
 	var b strings.Builder
+	buf := make([]byte, 1)
+
+	for b.Len() < length {
+		_, err := rand.Read(buf)
+
+		if err != nil {
+			panic(fmt.Sprintf("Failed to read from the secure random source: %v", err))
+		}
 
-	chars := []rune("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789")
+		if int(buf[0]) >= maxByte {
+			continue
+		}
 
-	for i := 0; i < length; i++ {
-		b.WriteRune(chars[rand.Intn(len(chars))])
+		b.WriteByte(chars[int(buf[0])%len(chars)])
 	}
 
 	return b.String()
@@ -284,10 +441,26 @@ func (s *CloudServer) SSH() (*ssh.Client, error) {
 		return nil, err
 	}
 
+	hostKeyCallback := ssh.InsecureIgnoreHostKey()
+
+	if !s.Driver.Configuration.InsecureIgnoreHostKey {
+		if s.Driver.Configuration.HostKeyStore == nil {
+			return nil, errors.New("A HostKeyStore must be configured to pin SSH host keys, or InsecureIgnoreHostKey must be set to opt out")
+		}
+
+		hostKey, err := s.Driver.Configuration.HostKeyStore.GetHostKey(s.Information.Identifier)
+
+		if err != nil {
+			return nil, err
+		}
+
+		hostKeyCallback = ssh.FixedHostKey(hostKey)
+	}
+
 	sshConfig := &ssh.ClientConfig{
 		User:            "root",
 		Auth:            []ssh.AuthMethod{ssh.PublicKeys(sshPrivateKeySigner)},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: hostKeyCallback,
 	}
 
 	sshClient, err := ssh.Dial("tcp", s.Information.NetworkInterfaces[0].IPAddresses[0].Address+":22", sshConfig)
@@ -0,0 +1,35 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package driver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetRandomPasswordLength(t *testing.T) {
+	s := &CloudServer{}
+
+	for _, length := range []int{0, 1, 16, 63, 128} {
+		password := s.GetRandomPassword(length)
+
+		if len(password) != length {
+			t.Errorf("GetRandomPassword(%d) returned a password of length %d", length, len(password))
+		}
+	}
+}
+
+func TestGetRandomPasswordCharset(t *testing.T) {
+	const allowed = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+	s := &CloudServer{}
+	password := s.GetRandomPassword(1000)
+
+	for _, r := range password {
+		if !strings.ContainsRune(allowed, r) {
+			t.Fatalf("GetRandomPassword produced an out-of-charset rune %q", r)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/danitso/terraform-provider-clouddk/clouddk"
+	"github.com/isgasho/clouddk-csi-driver/driver"
+	"github.com/isgasho/clouddk-csi-driver/driver/cloudservertest"
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "cloudservertest" {
+		os.Exit(runCloudServerTest(os.Args[2:]))
+	}
+
+	driver.Run(os.Args[1:])
+}
+
+// runCloudServerTest implements the `cloudservertest` subcommand, which
+// exercises the full Cloud.dk server lifecycle against a live account so
+// that operators can validate credentials, templates and SSH keys before
+// installing the CSI driver into a cluster.
+func runCloudServerTest(args []string) int {
+	fs := flag.NewFlagSet("cloudservertest", flag.ExitOnError)
+
+	token := fs.String("token", "", "Cloud.dk API token")
+	publicKey := fs.String("public-key", "", "SSH public key to authorize on the probe server")
+	privateKey := fs.String("private-key", "", "SSH private key used to connect to the probe server")
+	locationID := fs.String("location", "", "Cloud.dk location ID to create the probe server in")
+	packageID := fs.String("package", "", "Cloud.dk package ID to create the probe server with")
+	probeCommand := fs.String("probe-command", "", "Command to run over SSH on the probe server (default: true)")
+	insecureIgnoreHostKey := fs.Bool(
+		"insecure-ignore-host-key",
+		false,
+		"Skip SSH host key verification. The self-check is meant to run before the driver (and its HostKeyStore) is installed into a cluster, so there is nowhere to persist a pinned key yet; set this to accept that trade-off.",
+	)
+
+	fs.Parse(args)
+
+	if *token == "" || *locationID == "" || *packageID == "" {
+		fmt.Fprintln(os.Stderr, "cloudservertest: -token, -location and -package are required")
+
+		return 2
+	}
+
+	if !*insecureIgnoreHostKey {
+		fmt.Fprintln(os.Stderr, "cloudservertest: no HostKeyStore is available before the driver is installed; pass -insecure-ignore-host-key to run anyway")
+
+		return 2
+	}
+
+	d := &driver.Driver{
+		Configuration: driver.Configuration{
+			ClientSettings: &clouddk.ClientSettings{
+				Key: *token,
+			},
+			PublicKey:             *publicKey,
+			PrivateKey:            *privateKey,
+			InsecureIgnoreHostKey: *insecureIgnoreHostKey,
+		},
+	}
+
+	result, err := cloudservertest.Run(cloudservertest.Config{
+		Driver:       d,
+		LocationID:   *locationID,
+		PackageID:    *packageID,
+		ProbeCommand: *probeCommand,
+	})
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cloudservertest: self-check failed: %v\n", err)
+
+		return 1
+	}
+
+	fmt.Printf(
+		"cloudservertest: self-check passed (existing instances: %d, leaked instances cleaned: %d)\nprobe output:\n%s\n",
+		result.ExistingInstances,
+		result.LeakedInstancesCleaned,
+		result.ProbeOutput,
+	)
+
+	return 0
+}